@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeAIMoveTakesWinningMove(t *testing.T) {
+	// O has two in a row with the third cell open; a hard AI must take it
+	// instead of blocking or playing elsewhere.
+	board, width, height := boardFromRows([]string{
+		"OO...",
+		"XX...",
+		".....",
+	})
+	move := computeAIMove(board, width, height, 3, "O", "hard")
+	if move != 2 {
+		t.Fatalf("expected AI to complete its own winning line at position 2, got %d", move)
+	}
+}
+
+func TestComputeAIMoveBlocksOpponentWin(t *testing.T) {
+	// X has two in a row with the third cell open; a hard AI playing O must
+	// block rather than let X win next turn.
+	board, width, height := boardFromRows([]string{
+		"XX...",
+		".....",
+		"O...X",
+		".O...",
+	})
+	move := computeAIMove(board, width, height, 3, "O", "hard")
+	if move != 2 {
+		t.Fatalf("expected AI to block X's winning line at position 2, got %d", move)
+	}
+}
+
+func TestComputeAIMoveOnlyReturnsEmptyCells(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"XO.",
+		"OX.",
+		"...",
+	})
+	move := computeAIMove(board, width, height, 3, "O", "hard")
+	if board[move] != "" {
+		t.Fatalf("expected AI to move onto an empty cell, got occupied position %d", move)
+	}
+}
+
+func TestComputeAIMoveReturnsMinusOneOnFullBoard(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"XOX",
+		"XOX",
+		"OXO",
+	})
+	move := computeAIMove(board, width, height, 3, "O", "hard")
+	if move != -1 {
+		t.Fatalf("expected -1 for a full board, got %d", move)
+	}
+}
+
+func TestAIMoveRespectsBudgetOnLargeBoard(t *testing.T) {
+	width, height := 18, 18
+	board := make([]string, width*height)
+	board[0] = "X"
+
+	start := time.Now()
+	pos := computeAIMove(board, width, height, 5, "O", "hard")
+	elapsed := time.Since(start)
+
+	if pos < 0 {
+		t.Fatalf("expected a move, got %d", pos)
+	}
+	if elapsed > aiSearchBudget+500*time.Millisecond {
+		t.Fatalf("AI move took %s, exceeding budget %s by more than slack", elapsed, aiSearchBudget)
+	}
+}
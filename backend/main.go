@@ -2,7 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"math"
 	"math/rand"
 	"net/http"
 	"os"
@@ -15,11 +18,323 @@ import (
 	"github.com/rs/cors"
 )
 
+var (
+	errUnknownPlayer          = errors.New("unknown playerId for this game")
+	errPlayerAlreadyConnected = errors.New("player already has an active connection")
+	errGameFull               = errors.New("game already has two players")
+)
+
+// Player tracks one human participant in a Game: their identity, the symbol
+// they were assigned, and whichever websocket connection currently represents
+// them (nil while they are disconnected but still holding their slot).
+type Player struct {
+	ID     string       `json:"id"`
+	Symbol string       `json:"symbol"`
+	Conn   *MeteredConn `json:"-"`
+}
+
+// ClientID is the handshake frame every websocket client must send as its
+// first message, declaring whether it wants to play or merely watch.
+type ClientID struct {
+	Type   string `json:"type"` // "player" or "spectator"
+	Name   string `json:"name"`
+	GameID string `json:"gameId"`
+}
+
+// Failure is sent back to a client whose handshake or message was rejected.
+type Failure struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Client wraps a live websocket connection with the role it handshook as.
+// Unlike Player, a Client has no persistent slot - it exists only for the
+// lifetime of one connection and is what broadcasts are sent to.
+type Client struct {
+	Conn   *MeteredConn
+	Role   string // "player" or "spectator"
+	Symbol string // set only when Role == "player"
+	Name   string
+}
+
 type Game struct {
-	Board      []string `json:"board"`
-	NextPlayer string   `json:"nextPlayer"`
-	GameOver   bool     `json:"gameOver"`
-	Winner     string   `json:"winner"`
+	Board      []string           `json:"board"`
+	Width      int                `json:"width"`
+	Height     int                `json:"height"`
+	WinLength  int                `json:"winLength"`
+	Mode       string             `json:"mode"`
+	Difficulty string             `json:"difficulty,omitempty"`
+	NextPlayer string             `json:"nextPlayer"`
+	GameOver   bool               `json:"gameOver"`
+	Winner     string             `json:"winner"`
+	Passphrase string             `json:"passphrase"`
+	Players    map[string]*Player `json:"-"`
+	Stats      *GameStats         `json:"-"`
+	Bandwidth  *BandwidthStats    `json:"-"`
+
+	Moves         []MoveRecord `json:"moves"`
+	RematchCount  int          `json:"rematchCount"`
+	PendingUndoBy string       `json:"-"`
+}
+
+// MoveRecord is one entry in a game's move history, used to support undo and
+// to let handleRematchGame reset a lobby to a fresh board.
+type MoveRecord struct {
+	Position  int       `json:"position"`
+	Symbol    string    `json:"symbol"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GameStats accumulates aggregate numbers for a lobby across its lifetime
+// (including every rematch played in that lobby). It
+// carries its own lock so `GET /game/stats/{id}` can read it without
+// contending with the global `mu` that guards move processing.
+type GameStats struct {
+	mu         sync.RWMutex
+	TotalMoves int
+	StartedAt  time.Time
+	Wins       map[string]int
+	Losses     map[string]int
+	Draws      int
+}
+
+func newGameStats() *GameStats {
+	return &GameStats{
+		StartedAt: time.Now(),
+		Wins:      make(map[string]int),
+		Losses:    make(map[string]int),
+	}
+}
+
+func (s *GameStats) recordMove() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TotalMoves++
+}
+
+// recordResult updates win/loss/draw counts for one finished game. winner is
+// empty for a draw.
+func (s *GameStats) recordResult(winner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if winner == "" {
+		s.Draws++
+		return
+	}
+	s.Wins[winner]++
+	s.Losses[nextPlayer(winner)]++
+}
+
+// unrecordMove reverses one recordMove call, used when an undo pops a move
+// off the game's history.
+func (s *GameStats) unrecordMove() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TotalMoves--
+}
+
+// unrecordResult reverses one recordResult call, used when an undo pops a
+// move that had ended the game. winner must match what was passed to the
+// recordResult call being reversed.
+func (s *GameStats) unrecordResult(winner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if winner == "" {
+		s.Draws--
+		return
+	}
+	s.Wins[winner]--
+	s.Losses[nextPlayer(winner)]--
+}
+
+func (s *GameStats) movesPlayed() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.TotalMoves
+}
+
+func (s *GameStats) snapshot() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string]interface{}{
+		"totalMoves": s.TotalMoves,
+		"duration":   time.Since(s.StartedAt).Seconds(),
+		"wins":       s.Wins,
+		"losses":     s.Losses,
+		"draws":      s.Draws,
+	}
+}
+
+// bandwidthWindowSize is how many one-second buckets of tx/rx history
+// BandwidthStats keeps, enough for a minute-long sparkline.
+const bandwidthWindowSize = 60
+
+type bwBucket struct {
+	second int64
+	tx     int64
+	rx     int64
+}
+
+// BandwidthStats samples bytes written/read per game into a rolling window
+// of one-second buckets, so GET /game/bw/{id} can show tx/rx rates without
+// external metrics infrastructure.
+type BandwidthStats struct {
+	mu      sync.Mutex
+	buckets [bandwidthWindowSize]bwBucket
+}
+
+func newBandwidthStats() *BandwidthStats {
+	return &BandwidthStats{}
+}
+
+func (b *BandwidthStats) recordTx(n int) { b.record(n, 0) }
+func (b *BandwidthStats) recordRx(n int) { b.record(0, n) }
+
+func (b *BandwidthStats) record(tx, rx int) {
+	now := time.Now().Unix()
+	idx := int(((now % bandwidthWindowSize) + bandwidthWindowSize) % bandwidthWindowSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buckets[idx].second != now {
+		b.buckets[idx] = bwBucket{second: now}
+	}
+	b.buckets[idx].tx += int64(tx)
+	b.buckets[idx].rx += int64(rx)
+}
+
+// snapshot returns the last bandwidthWindowSize seconds of tx/rx byte
+// counts, oldest first, with gaps (no traffic that second) reported as 0.
+func (b *BandwidthStats) snapshot() (tx []int64, rx []int64) {
+	now := time.Now().Unix()
+	tx = make([]int64, bandwidthWindowSize)
+	rx = make([]int64, bandwidthWindowSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := 0; i < bandwidthWindowSize; i++ {
+		second := now - int64(bandwidthWindowSize-1-i)
+		idx := int(((second % bandwidthWindowSize) + bandwidthWindowSize) % bandwidthWindowSize)
+		if b.buckets[idx].second == second {
+			tx[i] = b.buckets[idx].tx
+			rx[i] = b.buckets[idx].rx
+		}
+	}
+	return tx, rx
+}
+
+// MeteredConn wraps a websocket connection so every JSON message sent or
+// received through it is sampled into the owning game's BandwidthStats.
+// Embedding *websocket.Conn promotes Close, WriteControl, etc. unchanged;
+// only WriteJSON/ReadJSON are overridden.
+type MeteredConn struct {
+	*websocket.Conn
+	stats *BandwidthStats
+}
+
+func newMeteredConn(conn *websocket.Conn, stats *BandwidthStats) *MeteredConn {
+	return &MeteredConn{Conn: conn, stats: stats}
+}
+
+func (m *MeteredConn) WriteJSON(v interface{}) error {
+	if data, err := json.Marshal(v); err == nil {
+		m.stats.recordTx(len(data))
+	}
+	return m.Conn.WriteJSON(v)
+}
+
+func (m *MeteredConn) ReadJSON(v interface{}) error {
+	err := m.Conn.ReadJSON(v)
+	if err == nil {
+		if data, merr := json.Marshal(v); merr == nil {
+			m.stats.recordRx(len(data))
+		}
+	}
+	return err
+}
+
+// GameConfig is the optional JSON body accepted by handleCreateGame and
+// handleHostGame to set up an m,n,k-game instead of classic 3x3 tic-tac-toe.
+type GameConfig struct {
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	WinLength  int    `json:"winLength"`
+	Mode       string `json:"mode"`
+	Difficulty string `json:"difficulty"`
+}
+
+// aiSymbol is the symbol the server always plays for itself in "ai" mode;
+// the human occupant of a lobby is seated as "X" and moves first.
+const aiSymbol = "O"
+
+var validDifficulties = map[string]bool{"easy": true, "medium": true, "hard": true}
+
+// maxBoardCells caps width*height so a client can't make the server allocate
+// an unreasonably large board.
+const maxBoardCells = 400
+
+// parseGameConfig reads an optional GameConfig body, fills in the classic
+// 3x3x3 defaults for anything left unset, and validates the result. An empty
+// body is valid and yields the classic game.
+func parseGameConfig(r *http.Request) (GameConfig, error) {
+	cfg := GameConfig{Width: 3, Height: 3, WinLength: 3, Mode: "classic"}
+
+	if r.Body != nil {
+		var body GameConfig
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, errors.New("invalid game config JSON")
+		}
+		if body.Width != 0 {
+			cfg.Width = body.Width
+		}
+		if body.Height != 0 {
+			cfg.Height = body.Height
+		}
+		if body.WinLength != 0 {
+			cfg.WinLength = body.WinLength
+		}
+		if body.Mode != "" {
+			cfg.Mode = body.Mode
+		}
+		if body.Difficulty != "" {
+			cfg.Difficulty = body.Difficulty
+		}
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return cfg, errors.New("width and height must be positive")
+	}
+	if cfg.Width*cfg.Height > maxBoardCells {
+		return cfg, errors.New("board is too large")
+	}
+	min := cfg.Width
+	if cfg.Height < min {
+		min = cfg.Height
+	}
+	if cfg.WinLength <= 0 || cfg.WinLength > min {
+		return cfg, errors.New("winLength must be positive and no greater than min(width, height)")
+	}
+
+	if cfg.Mode == "ai" {
+		if cfg.Difficulty == "" {
+			cfg.Difficulty = "medium"
+		}
+		if !validDifficulties[cfg.Difficulty] {
+			return cfg, errors.New("difficulty must be easy, medium, or hard")
+		}
+	}
+
+	return cfg, nil
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
 type Move struct {
@@ -31,22 +346,98 @@ type Move struct {
 
 var (
 	games       = make(map[string]*Game)
-	connections = make(map[string][]*websocket.Conn)
+	lobbies     = make(map[string]string) // passphrase -> gameID
+	connections = make(map[string][]*Client)
 	mu          sync.RWMutex
 )
 
 func handleCreateGame(w http.ResponseWriter, r *http.Request) {
+	cfg, err := parseGameConfig(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
 	mu.Lock()
 	gameID := generateGameID()
 	games[gameID] = &Game{
-		Board:      make([]string, 9),
+		Board:      make([]string, cfg.Width*cfg.Height),
+		Width:      cfg.Width,
+		Height:     cfg.Height,
+		WinLength:  cfg.WinLength,
+		Mode:       cfg.Mode,
+		Difficulty: cfg.Difficulty,
 		NextPlayer: "X",
+		Players:    make(map[string]*Player),
+		Stats:      newGameStats(),
+		Bandwidth:  newBandwidthStats(),
 	}
 	mu.Unlock()
 
-	log.Printf("Created new game: %s", gameID)
+	log.Printf("Created new game: %s (%dx%d, winLength=%d, mode=%s)", gameID, cfg.Width, cfg.Height, cfg.WinLength, cfg.Mode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"gameId": gameID,
+	})
+}
+
+// handleHostGame creates a game the same way handleCreateGame does, but also
+// registers a human-friendly passphrase for rejoining and seats the caller as
+// the first player (symbol "X") so a browser refresh or dropped connection
+// doesn't lose their seat.
+func handleHostGame(w http.ResponseWriter, r *http.Request) {
+	cfg, err := parseGameConfig(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	mu.Lock()
+	gameID := generateGameID()
+	passphrase := generatePassphrase()
+	player := &Player{ID: generatePlayerID(), Symbol: "X"}
+	games[gameID] = &Game{
+		Board:      make([]string, cfg.Width*cfg.Height),
+		Width:      cfg.Width,
+		Height:     cfg.Height,
+		WinLength:  cfg.WinLength,
+		Mode:       cfg.Mode,
+		Difficulty: cfg.Difficulty,
+		NextPlayer: "X",
+		Passphrase: passphrase,
+		Players:    map[string]*Player{player.ID: player},
+		Stats:      newGameStats(),
+		Bandwidth:  newBandwidthStats(),
+	}
+	lobbies[passphrase] = gameID
+	mu.Unlock()
+
+	log.Printf("Hosted new game: %s (passphrase %s)", gameID, passphrase)
+	json.NewEncoder(w).Encode(map[string]string{
+		"gameId":     gameID,
+		"playerId":   player.ID,
+		"passphrase": passphrase,
+	})
+}
+
+func handleResolveLobby(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	passphrase := vars["passphrase"]
+
+	mu.RLock()
+	gameID, exists := lobbies[passphrase]
+	mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		return
+	}
+
 	json.NewEncoder(w).Encode(map[string]string{
 		"gameId": gameID,
 	})
@@ -74,9 +465,206 @@ func handleJoinGame(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleListGames reports every active lobby, enough for an operator to see
+// what's running without connecting to any single game's websocket.
+func handleListGames(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]map[string]interface{}, 0, len(games))
+	for id, game := range games {
+		list = append(list, map[string]interface{}{
+			"id":          id,
+			"playerCount": len(game.Players),
+			"movesPlayed": game.Stats.movesPlayed(),
+			"gameOver":    game.GameOver,
+			"winner":      game.Winner,
+		})
+	}
+
+	json.NewEncoder(w).Encode(list)
+}
+
+func handleGameStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameID := vars["id"]
+
+	mu.RLock()
+	game, exists := games[gameID]
+	mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game.Stats.snapshot())
+}
+
+// handleGameBandwidth reports the last minute of tx/rx traffic for a game,
+// useful for diagnosing a sluggish websocket session without hooking up
+// external metrics infrastructure.
+func handleGameBandwidth(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameID := vars["id"]
+
+	mu.RLock()
+	game, exists := games[gameID]
+	clientCount := len(connections[gameID])
+	mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+
+	tx, rx := game.Bandwidth.snapshot()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tx":               tx,
+		"rx":               rx,
+		"connectedClients": clientCount,
+	})
+}
+
+// handleStopGame ends a game immediately: it marks it over, tells every
+// connected client, closes their sockets, and forgets the lobby entirely.
+func handleStopGame(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameID := vars["id"]
+
+	mu.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		mu.Unlock()
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	game.GameOver = true
+	conns := connections[gameID]
+	delete(games, gameID)
+	delete(connections, gameID)
+	if game.Passphrase != "" {
+		delete(lobbies, game.Passphrase)
+	}
+	mu.Unlock()
+
+	log.Printf("Stopped game: %s", gameID)
+	broadcastToConns(conns, map[string]interface{}{"type": "GAME_STOPPED"})
+	for _, client := range conns {
+		client.Conn.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "stopped",
+	})
+}
+
+// handleRematchGame resets a finished lobby for another round: fresh board,
+// but the loser of the previous round (or the other symbol, on a draw) goes
+// first this time, and RematchCount keeps the lobby's stats (Stats never
+// resets) attributable across every round played in it.
+func handleRematchGame(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameID := vars["id"]
+
+	mu.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		mu.Unlock()
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	if !game.GameOver {
+		mu.Unlock()
+		writeJSONError(w, http.StatusConflict, "game is still in progress")
+		return
+	}
+
+	if game.Winner != "" {
+		game.NextPlayer = nextPlayer(game.Winner)
+	} else if len(game.Moves) > 0 {
+		// A draw: the symbol that did not open the finished round opens
+		// this one. Read before Moves is cleared below.
+		game.NextPlayer = nextPlayer(game.Moves[0].Symbol)
+	}
+	game.Board = make([]string, game.Width*game.Height)
+	game.Moves = nil
+	game.PendingUndoBy = ""
+	game.RematchCount++
+	game.GameOver = false
+	game.Winner = ""
+	rematchCount := game.RematchCount
+	mu.Unlock()
+
+	log.Printf("Game %s rematch #%d, %s goes first", gameID, rematchCount, game.NextPlayer)
+	broadcastGameState(gameID, "REMATCH")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "rematch",
+		"rematchCount": rematchCount,
+		"nextPlayer":   game.NextPlayer,
+	})
+}
+
+// requestUndo implements the two-party consent flow for UNDO: the first
+// player to ask sets PendingUndoBy and nothing else happens; only when the
+// *other* player also asks does the last move actually get popped.
+func requestUndo(gameID, playerID string) {
+	mu.Lock()
+
+	game := games[gameID]
+	if game == nil || len(game.Moves) == 0 {
+		mu.Unlock()
+		return
+	}
+
+	if game.PendingUndoBy == "" {
+		game.PendingUndoBy = playerID
+		mu.Unlock()
+		broadcastGameState(gameID, "UNDO_REQUESTED")
+		return
+	}
+
+	if game.PendingUndoBy == playerID {
+		// Same player asking again while a request is already pending; not
+		// a second, independent consent, so do nothing.
+		mu.Unlock()
+		return
+	}
+
+	last := game.Moves[len(game.Moves)-1]
+	game.Moves = game.Moves[:len(game.Moves)-1]
+	game.Board[last.Position] = ""
+	game.NextPlayer = last.Symbol
+
+	// If the move being undone was the one that ended the game, reverse the
+	// result it recorded before clearing GameOver/Winner - otherwise the win,
+	// loss, or draw it contributed stays counted in Stats forever, even
+	// though the game it belonged to no longer exists.
+	if game.GameOver {
+		game.Stats.unrecordResult(game.Winner)
+	}
+	game.GameOver = false
+	game.Winner = ""
+	game.Stats.unrecordMove()
+	game.PendingUndoBy = ""
+	mu.Unlock()
+
+	log.Printf("Game %s undid move at position %d", gameID, last.Position)
+	broadcastGameState(gameID, "UNDO")
+}
+
 func handleWebSocket(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader) {
 	vars := mux.Vars(r)
 	gameID := vars["id"]
+	playerID := r.URL.Query().Get("playerId")
 
 	// Check if game exists
 	mu.RLock()
@@ -88,23 +676,82 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, upgrader *websocket
 		return
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	rawConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
+	conn := newMeteredConn(rawConn, game.Bandwidth)
+
+	// The handshake is always the first frame: it tells the server whether
+	// this connection wants to play or just watch.
+	var handshake ClientID
+	if err := conn.ReadJSON(&handshake); err != nil {
+		log.Printf("Error reading handshake for game %s: %v", gameID, err)
+		conn.Close()
+		return
+	}
+	if handshake.Type != "player" && handshake.Type != "spectator" {
+		conn.WriteJSON(Failure{Type: "FAILURE", Message: "client type must be \"player\" or \"spectator\""})
+		conn.Close()
+		return
+	}
+
+	var player *Player
+	role := handshake.Type
+	if role == "player" {
+		player, err = attachPlayer(game, playerID, conn)
+		switch {
+		case err == nil:
+			// seated fine
+		case errors.Is(err, errGameFull):
+			log.Printf("Game %s already has two players, demoting connection to spectator", gameID)
+			role = "spectator"
+		case errors.Is(err, errPlayerAlreadyConnected):
+			// A second socket for a playerId that's already connected is a
+			// conflict, not a missing resource - distinct close code from
+			// errUnknownPlayer below.
+			log.Printf("Rejecting websocket for game %s: %v", gameID, err)
+			closeMsg := websocket.FormatCloseMessage(4009, err.Error())
+			conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+			conn.Close()
+			return
+		default:
+			log.Printf("Rejecting websocket for game %s: %v", gameID, err)
+			closeMsg := websocket.FormatCloseMessage(4004, err.Error())
+			conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+			conn.Close()
+			return
+		}
+	}
+
+	client := &Client{Conn: conn, Role: role, Name: handshake.Name}
+	if player != nil {
+		client.Symbol = player.Symbol
+	}
 
-	// Add this connection to the game
 	mu.Lock()
-	connections[gameID] = append(connections[gameID], conn)
+	connections[gameID] = append(connections[gameID], client)
 	mu.Unlock()
 
-	log.Printf("WebSocket connection established for game: %s", gameID)
+	log.Printf("WebSocket connection established for game: %s (role=%s, name=%q)", gameID, client.Role, client.Name)
 
-	// Send initial game state
-	if err := conn.WriteJSON(game); err != nil {
+	// Send initial game state, plus the identity the client should remember
+	// so it can reconnect under the same player slot later.
+	initialState := map[string]interface{}{
+		"board":      game.Board,
+		"nextPlayer": game.NextPlayer,
+		"gameOver":   game.GameOver,
+		"winner":     game.Winner,
+		"role":       client.Role,
+	}
+	if player != nil {
+		initialState["playerId"] = player.ID
+		initialState["symbol"] = player.Symbol
+	}
+	if err := conn.WriteJSON(initialState); err != nil {
 		log.Printf("Error sending initial state: %v", err)
-		removeConnection(gameID, conn)
+		removeConnection(gameID, client)
 		return
 	}
 
@@ -114,69 +761,365 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, upgrader *websocket
 		err := conn.ReadJSON(&move)
 		if err != nil {
 			log.Printf("WebSocket read error: %v", err)
-			removeConnection(gameID, conn)
+			removeConnection(gameID, client)
+			if player != nil {
+				detachPlayer(game, player.ID, conn)
+			}
 			break
 		}
 
 		log.Printf("Received move: %+v", move)
 
-		if move.Type == "MOVE" {
-			makeMove(gameID, move.Position, move.Symbol)
+		switch move.Type {
+		case "MOVE":
+			if client.Role != "player" {
+				conn.WriteJSON(Failure{Type: "FAILURE", Message: "spectators cannot make moves"})
+				continue
+			}
+			processMove(gameID, move.Position, move.Symbol)
+		case "UNDO":
+			if client.Role != "player" {
+				conn.WriteJSON(Failure{Type: "FAILURE", Message: "spectators cannot request undo"})
+				continue
+			}
+			requestUndo(gameID, player.ID)
 		}
 	}
 }
 
-func makeMove(gameID string, position int, symbol string) {
+// attachPlayer seats conn as the holder of playerID's slot. If playerID is
+// empty, a fresh slot is claimed (as "X" then "O"); if it names an existing
+// slot whose connection already holds the line, the new connection is
+// rejected so a stray duplicate tab can't steal or shadow an active player.
+func attachPlayer(game *Game, playerID string, conn *MeteredConn) (*Player, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if playerID != "" {
+		player, ok := game.Players[playerID]
+		if !ok {
+			return nil, errUnknownPlayer
+		}
+		if player.Conn != nil {
+			return nil, errPlayerAlreadyConnected
+		}
+		player.Conn = conn
+		return player, nil
+	}
+
+	maxPlayers := 2
+	if game.Mode == "ai" {
+		// The AI itself occupies "O"; only one human seat is available.
+		maxPlayers = 1
+	}
+	if len(game.Players) >= maxPlayers {
+		return nil, errGameFull
+	}
+	symbol := "X"
+	if len(game.Players) == 1 {
+		symbol = "O"
+	}
+	player := &Player{ID: generatePlayerID(), Symbol: symbol, Conn: conn}
+	game.Players[player.ID] = player
+	return player, nil
+}
+
+// detachPlayer clears the connection from a player's slot (without removing
+// the slot itself) so a later reconnect with the same playerId can reclaim it.
+func detachPlayer(game *Game, playerID string, conn *MeteredConn) {
 	mu.Lock()
 	defer mu.Unlock()
 
+	if player, ok := game.Players[playerID]; ok && player.Conn == conn {
+		player.Conn = nil
+	}
+}
+
+// makeMove must release mu before calling broadcastGameState: broadcastGameState
+// takes mu.RLock itself, and mu is not reentrant, so holding the write lock
+// across the call would deadlock this goroutine - and since mu is the single
+// global lock every handler uses, it would wedge the whole server.
+func makeMove(gameID string, position int, symbol string) {
+	mu.Lock()
+
 	game := games[gameID]
 	if game == nil {
+		mu.Unlock()
 		log.Printf("Game not found: %s", gameID)
 		return
 	}
 
 	if game.GameOver {
+		mu.Unlock()
 		log.Printf("Game is already over")
 		return
 	}
 
-	if position < 0 || position >= 9 {
+	if position < 0 || position >= len(game.Board) {
+		mu.Unlock()
 		log.Printf("Invalid position: %d", position)
 		return
 	}
 
 	if game.Board[position] != "" {
+		mu.Unlock()
 		log.Printf("Position already occupied: %d", position)
 		return
 	}
 
 	if symbol != game.NextPlayer {
+		mu.Unlock()
 		log.Printf("Not player's turn. Expected %s, got %s", game.NextPlayer, symbol)
 		return
 	}
 
 	game.Board[position] = symbol
 	game.NextPlayer = nextPlayer(symbol)
+	game.Stats.recordMove()
+	game.Moves = append(game.Moves, MoveRecord{Position: position, Symbol: symbol, Timestamp: time.Now()})
 
-	if checkWin(game.Board) {
+	broadcastType := "MOVE"
+	if checkWin(game.Board, game.Width, game.Height, game.WinLength, position) {
 		game.GameOver = true
 		game.Winner = symbol
+		game.Stats.recordResult(symbol)
 		log.Printf("Game %s won by %s", gameID, symbol)
-		broadcastGameState(gameID, "GAME_OVER")
+		broadcastType = "GAME_OVER"
 	} else if checkDraw(game.Board) {
 		game.GameOver = true
+		game.Stats.recordResult("")
 		log.Printf("Game %s ended in a draw", gameID)
-		broadcastGameState(gameID, "GAME_OVER")
-	} else {
-		broadcastGameState(gameID, "MOVE")
+		broadcastType = "GAME_OVER"
 	}
+
+	mu.Unlock()
+	broadcastGameState(gameID, broadcastType)
+}
+
+// processMove applies a move and, if it leaves an "ai"-mode game waiting on
+// the CPU, immediately computes and applies the AI's reply so clients see a
+// natural back-and-forth move stream. It must not be called while mu is
+// held: makeMove takes its own lock per call.
+func processMove(gameID string, position int, symbol string) {
+	makeMove(gameID, position, symbol)
+
+	mu.RLock()
+	game, exists := games[gameID]
+	mu.RUnlock()
+	if !exists || game.Mode != "ai" || game.GameOver || game.NextPlayer != aiSymbol {
+		return
+	}
+
+	mu.RLock()
+	board := append([]string(nil), game.Board...)
+	mu.RUnlock()
+
+	aiMove := computeAIMove(board, game.Width, game.Height, game.WinLength, aiSymbol, game.Difficulty)
+	if aiMove < 0 {
+		return
+	}
+	makeMove(gameID, aiMove, aiSymbol)
+}
+
+// aiSearchBudget bounds how long one computeAIMove call is allowed to spend
+// in minimax, independent of the ply cap: on a wide board the ply cap alone
+// doesn't bound node count (branching factor grows with board size), so a
+// wall-clock deadline is what actually keeps a "hard" move off a huge board
+// from pegging the connection's goroutine.
+const aiSearchBudget = 1500 * time.Millisecond
+
+// aiSearch carries the deadline for one computeAIMove call through the
+// minimax recursion. Checking the clock on every node would itself be
+// expensive at depth, so it's only sampled every aiSearchCheckInterval nodes.
+type aiSearch struct {
+	deadline time.Time
+	nodes    int
+	timedOut bool
+}
+
+const aiSearchCheckInterval = 1024
+
+// outOfTime reports whether the search budget has been exceeded. Once it has,
+// every subsequent call returns true immediately off the cached timedOut
+// flag, so the recursion unwinds in one pass rather than continuing to
+// explore 1023 out of every 1024 branches at full depth; until then the
+// time.Now() syscall is only sampled once per aiSearchCheckInterval nodes to
+// keep the check itself cheap.
+func (s *aiSearch) outOfTime() bool {
+	if s.timedOut {
+		return true
+	}
+	s.nodes++
+	if s.nodes%aiSearchCheckInterval != 0 {
+		return false
+	}
+	if time.Now().After(s.deadline) {
+		s.timedOut = true
+	}
+	return s.timedOut
+}
+
+// computeAIMove picks aiSymbol's next move via minimax with alpha-beta
+// pruning, searching to a depth capped by difficulty: "easy" looks one ply
+// ahead (and is additionally nerfed with a 30% chance of playing a random
+// legal move instead), "medium" looks four plies ahead, and "hard" searches
+// a board of up to 9 cells to the end and larger boards five plies ahead.
+// Every search also stops once aiSearchBudget has elapsed.
+func computeAIMove(board []string, width, height, winLength int, aiSymbol, difficulty string) int {
+	empties := emptyCells(board)
+	if len(empties) == 0 {
+		return -1
+	}
+
+	if difficulty == "easy" && rand.Float64() < 0.3 {
+		return empties[rand.Intn(len(empties))]
+	}
+
+	opponent := nextPlayer(aiSymbol)
+	maxPly := aiSearchDepth(difficulty, len(board))
+	search := &aiSearch{deadline: time.Now().Add(aiSearchBudget)}
+
+	bestScore := math.MinInt32
+	bestMove := empties[0]
+	alpha, beta := math.MinInt32, math.MaxInt32
+
+	for _, pos := range empties {
+		board[pos] = aiSymbol
+		score := minimax(search, board, width, height, winLength, 1, maxPly, false, aiSymbol, opponent, alpha, beta, pos)
+		board[pos] = ""
+
+		if search.timedOut {
+			// The budget ran out partway through this move's subtree, so its
+			// score may be an artificially neutral 0 from branches that got
+			// cut short rather than a real evaluation - don't let it
+			// displace a move that was fully evaluated before the deadline.
+			log.Printf("AI search for difficulty=%s exceeded %s budget; returning best move found so far", difficulty, aiSearchBudget)
+			break
+		}
+
+		if score > bestScore {
+			bestScore = score
+			bestMove = pos
+		}
+		if bestScore > alpha {
+			alpha = bestScore
+		}
+	}
+
+	return bestMove
+}
+
+// minimax scores the position reached by the move just played at lastMove,
+// recursing until the game ends, ply reaches maxPly, or the search budget in
+// search runs out (at which point the position is scored neutrally, same as
+// a depth cutoff). Wins/losses are scored relative to ply so the AI prefers
+// a faster win and a slower loss.
+func minimax(search *aiSearch, board []string, width, height, winLength, ply, maxPly int, maximizing bool, aiSymbol, opponent string, alpha, beta, lastMove int) int {
+	if checkWin(board, width, height, winLength, lastMove) {
+		if board[lastMove] == aiSymbol {
+			return 10 - ply
+		}
+		return ply - 10
+	}
+
+	empties := emptyCells(board)
+	if len(empties) == 0 || ply >= maxPly || search.outOfTime() {
+		return 0
+	}
+
+	symbol := aiSymbol
+	if !maximizing {
+		symbol = opponent
+	}
+
+	if maximizing {
+		best := math.MinInt32
+		for _, pos := range empties {
+			board[pos] = symbol
+			score := minimax(search, board, width, height, winLength, ply+1, maxPly, false, aiSymbol, opponent, alpha, beta, pos)
+			board[pos] = ""
+
+			if score > best {
+				best = score
+			}
+			if best > alpha {
+				alpha = best
+			}
+			if alpha >= beta {
+				break
+			}
+		}
+		return best
+	}
+
+	best := math.MaxInt32
+	for _, pos := range empties {
+		board[pos] = symbol
+		score := minimax(search, board, width, height, winLength, ply+1, maxPly, true, aiSymbol, opponent, alpha, beta, pos)
+		board[pos] = ""
+
+		if score < best {
+			best = score
+		}
+		if best < beta {
+			beta = best
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// aiSearchDepth maps a difficulty label to a ply budget. "hard" is left
+// effectively unbounded on a classic 3x3 board and capped on larger boards
+// so a minimax search still completes quickly.
+func aiSearchDepth(difficulty string, totalCells int) int {
+	switch difficulty {
+	case "easy":
+		return 1
+	case "medium":
+		return 4
+	default: // "hard"
+		if totalCells <= 9 {
+			return totalCells
+		}
+		return 5
+	}
+}
+
+func emptyCells(board []string) []int {
+	cells := make([]int, 0, len(board))
+	for i, cell := range board {
+		if cell == "" {
+			cells = append(cells, i)
+		}
+	}
+	return cells
 }
 
 func generateGameID() string {
 	return "game_" + randomString(6)
 }
 
+func generatePlayerID() string {
+	return "player_" + randomString(12)
+}
+
+var passphraseWords = []string{
+	"amber", "brisk", "cedar", "delta", "ember", "frost", "gusty", "haven",
+	"indigo", "jolly", "karma", "lunar", "maple", "noble", "otter", "piano",
+	"quartz", "raven", "spark", "tango", "umbra", "vivid", "willow", "zebra",
+}
+
+// generatePassphrase builds a human-friendly "word-word-NN" identifier that
+// is far easier to read over voice or type by hand than a game_xxxxxx ID.
+func generatePassphrase() string {
+	a := passphraseWords[rand.Intn(len(passphraseWords))]
+	b := passphraseWords[rand.Intn(len(passphraseWords))]
+	return a + "-" + b + "-" + randomString(2)
+}
+
 func randomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	result := make([]byte, length)
@@ -208,50 +1151,89 @@ func broadcastGameState(gameID string, messageType string) {
 	}
 
 	mu.RLock()
-	gameCons := connections[gameID]
+	gameClients := connections[gameID]
 	mu.RUnlock()
 
-	for _, conn := range gameCons {
-		if err := conn.WriteJSON(message); err != nil {
+	for _, client := range gameClients {
+		if err := client.Conn.WriteJSON(message); err != nil {
 			log.Printf("Error broadcasting to client: %v", err)
-			removeConnection(gameID, conn)
+			removeConnection(gameID, client)
 		}
 	}
 }
 
-func removeConnection(gameID string, conn *websocket.Conn) {
+// broadcastToConns writes message to an explicit list of clients,
+// independent of a live entry in the connections map. Used by handlers that
+// have already removed the game (e.g. handleStopGame) but still hold the
+// sockets to notify.
+func broadcastToConns(clients []*Client, message interface{}) {
+	for _, client := range clients {
+		if err := client.Conn.WriteJSON(message); err != nil {
+			log.Printf("Error broadcasting to client: %v", err)
+		}
+	}
+}
+
+func removeConnection(gameID string, client *Client) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	if conns, exists := connections[gameID]; exists {
-		for i, c := range conns {
-			if c == conn {
-				connections[gameID] = append(conns[:i], conns[i+1:]...)
+	if clients, exists := connections[gameID]; exists {
+		for i, c := range clients {
+			if c == client {
+				connections[gameID] = append(clients[:i], clients[i+1:]...)
 				break
 			}
 		}
 	}
 
-	conn.Close()
+	client.Conn.Close()
 }
 
-func checkWin(board []string) bool {
-	winPatterns := [][]int{
-		{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, // Rows
-		{0, 3, 6}, {1, 4, 7}, {2, 5, 8}, // Columns
-		{0, 4, 8}, {2, 4, 6}, // Diagonals
+// checkWin reports whether the move just played at index p completed a run
+// of winLength matching symbols on a width x height board. Rather than
+// enumerating every possible winning line (which only works for fixed, small
+// boards), it scans outward from p along the four axes a move can win
+// on - horizontal, vertical, and the two diagonals - counting how many
+// consecutive cells on each side share p's symbol. This is O(winLength) per
+// move regardless of board size.
+func checkWin(board []string, width, height, winLength, p int) bool {
+	symbol := board[p]
+	if symbol == "" {
+		return false
 	}
 
-	for _, pattern := range winPatterns {
-		if board[pattern[0]] != "" &&
-			board[pattern[0]] == board[pattern[1]] &&
-			board[pattern[1]] == board[pattern[2]] {
+	row, col := p/width, p%width
+	directions := [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+
+	for _, d := range directions {
+		count := 1
+		count += countDirection(board, width, height, row, col, d[0], d[1], symbol)
+		count += countDirection(board, width, height, row, col, -d[0], -d[1], symbol)
+		if count >= winLength {
 			return true
 		}
 	}
 	return false
 }
 
+// countDirection counts consecutive cells matching symbol, starting one step
+// from (row, col) in the (dRow, dCol) direction and stopping at the board
+// edge or the first non-matching cell.
+func countDirection(board []string, width, height, row, col, dRow, dCol int, symbol string) int {
+	count := 0
+	r, c := row+dRow, col+dCol
+	for r >= 0 && r < height && c >= 0 && c < width {
+		if board[r*width+c] != symbol {
+			break
+		}
+		count++
+		r += dRow
+		c += dCol
+	}
+	return count
+}
+
 func checkDraw(board []string) bool {
 	for _, cell := range board {
 		if cell == "" {
@@ -315,7 +1297,14 @@ func main() {
 
 	// Routes
 	r.HandleFunc("/game/create", handleCreateGame).Methods("POST", "OPTIONS")
+	r.HandleFunc("/game/host", handleHostGame).Methods("POST", "OPTIONS")
 	r.HandleFunc("/game/join/{id}", handleJoinGame).Methods("POST", "OPTIONS")
+	r.HandleFunc("/lobby/{passphrase}", handleResolveLobby).Methods("GET", "OPTIONS")
+	r.HandleFunc("/game/list", handleListGames).Methods("GET")
+	r.HandleFunc("/game/stats/{id}", handleGameStats).Methods("GET")
+	r.HandleFunc("/game/bw/{id}", handleGameBandwidth).Methods("GET")
+	r.HandleFunc("/game/stop/{id}", handleStopGame).Methods("POST", "OPTIONS")
+	r.HandleFunc("/game/rematch/{id}", handleRematchGame).Methods("POST", "OPTIONS")
 	r.HandleFunc("/ws/{id}", func(w http.ResponseWriter, r *http.Request) {
 		handleWebSocket(w, r, &upgrader)
 	})
@@ -0,0 +1,117 @@
+package main
+
+import "testing"
+
+// boardFromRows builds a board slice from row strings where each rune is a
+// cell: "X"/"O" literally, anything else (e.g. ".") is empty.
+func boardFromRows(rows []string) (board []string, width, height int) {
+	height = len(rows)
+	width = len(rows[0])
+	board = make([]string, width*height)
+	for r, row := range rows {
+		for c, ch := range row {
+			if ch == 'X' || ch == 'O' {
+				board[r*width+c] = string(ch)
+			}
+		}
+	}
+	return board, width, height
+}
+
+func TestCheckWinHorizontal(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"XXX..",
+		".....",
+	})
+	if !checkWin(board, width, height, 3, 2) {
+		t.Fatal("expected a horizontal win across row 0")
+	}
+}
+
+func TestCheckWinVertical(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"X....",
+		"X....",
+		"X....",
+	})
+	if !checkWin(board, width, height, 3, 2*5) {
+		t.Fatal("expected a vertical win down column 0")
+	}
+}
+
+func TestCheckWinDiagonal(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"X....",
+		".X...",
+		"..X..",
+	})
+	if !checkWin(board, width, height, 3, 2*5+2) {
+		t.Fatal("expected a diagonal win")
+	}
+}
+
+func TestCheckWinAntiDiagonal(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"..X..",
+		".X...",
+		"X....",
+	})
+	if !checkWin(board, width, height, 3, 2*5) {
+		t.Fatal("expected an anti-diagonal win")
+	}
+}
+
+func TestCheckWinNoWrapAcrossRows(t *testing.T) {
+	// Last two cells of row 0 and first cell of row 1 are collinear only if
+	// the scan is allowed to wrap past the board edge - it must not be.
+	board, width, height := boardFromRows([]string{
+		"..XX",
+		"X...",
+	})
+	if checkWin(board, width, height, 3, 3) {
+		t.Fatal("expected no win: a horizontal run must not wrap to the next row")
+	}
+}
+
+func TestCheckWinRequiresFullLength(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"XX...",
+	})
+	if checkWin(board, width, height, 3, 1) {
+		t.Fatal("expected no win: only two in a row, winLength is 3")
+	}
+}
+
+func TestCheckWinSingleColumnBoard(t *testing.T) {
+	board := []string{"X", "X", "X"}
+	if !checkWin(board, 1, 3, 3, 2) {
+		t.Fatal("expected a win on a width=1 board stacked vertically")
+	}
+}
+
+func TestCheckWinEmptyCellNeverWins(t *testing.T) {
+	board, width, height := boardFromRows([]string{
+		"XXX..",
+	})
+	if checkWin(board, width, height, 3, 3) {
+		t.Fatal("expected no win when checking an empty cell")
+	}
+}
+
+func TestCheckDraw(t *testing.T) {
+	full, width, height := boardFromRows([]string{
+		"XOX",
+		"XOX",
+		"OXO",
+	})
+	_ = width
+	_ = height
+	if !checkDraw(full) {
+		t.Fatal("expected a full board to be a draw")
+	}
+
+	full[4] = ""
+	if checkDraw(full) {
+		t.Fatal("expected a board with an empty cell to not be a draw")
+	}
+}